@@ -0,0 +1,108 @@
+package bloombits
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vechain/thor/thor"
+)
+
+// memStore is a minimal in-memory KeyValueStore for testing the Indexer
+// without a real lvldb instance.
+type memStore map[string][]byte
+
+func (m memStore) Get(key []byte) ([]byte, error) {
+	v, ok := m[string(key)]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return v, nil
+}
+
+func (m memStore) Put(key []byte, value []byte) error {
+	m[string(key)] = value
+	return nil
+}
+
+func TestGeneratorRotatesBits(t *testing.T) {
+	gen, err := NewGenerator(8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bloom thor.Bloom
+	bloom.Add([]byte("hello"))
+
+	for i := uint64(0); i < 8; i++ {
+		b := thor.Bloom{}
+		if i == 3 {
+			b = bloom
+		}
+		if err := gen.AddBloom(i, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every bit position set in bloom must have its rotated bit-vector record
+	// block 3 (byte 0, bit 1<<4) as set; every bit position not set in bloom
+	// must not.
+	for bit := 0; bit < thor.BloomBitLength; bit++ {
+		bitset, err := gen.Bitset(uint(bit))
+		if err != nil {
+			t.Fatal(err)
+		}
+		byteIdx := thor.BloomByteLength - 1 - bit/8
+		bitMask := byte(1) << byte(bit%8)
+		want := bloom[byteIdx]&bitMask != 0
+		got := bitset[0]&(1<<4) != 0
+		if got != want {
+			t.Fatalf("bit %d: rotated vector recorded %v, want %v", bit, got, want)
+		}
+	}
+}
+
+func TestIndexerIndexedUpTo(t *testing.T) {
+	db := memStore{}
+	idx, err := NewIndexer(db, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := idx.IndexedUpTo(); ok {
+		t.Fatal("expected no section to be indexed yet")
+	}
+
+	for i := uint64(0); i < 8; i++ {
+		if err := idx.Add(i, thor.Bloom{}); err != nil {
+			t.Fatal(err)
+		}
+		if i < 7 {
+			if _, ok := idx.IndexedUpTo(); ok {
+				t.Fatal("section should not be marked complete before its last block is added")
+			}
+		}
+	}
+
+	upTo, ok := idx.IndexedUpTo()
+	if !ok || upTo != 7 {
+		t.Fatalf("expected IndexedUpTo() = (7, true), got (%d, %v)", upTo, ok)
+	}
+
+	// The completed section's bit-vectors must actually be retrievable.
+	bitsets, err := idx.Retrieve(context.Background(), 0, []uint64{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bitsets) != 1 || len(bitsets[0]) != 1 {
+		t.Fatalf("expected one 1-byte bitset for section 0, got %v", bitsets)
+	}
+
+	// A second section must start out incomplete again.
+	if err := idx.Add(8, thor.Bloom{}); err != nil {
+		t.Fatal(err)
+	}
+	if upTo, _ := idx.IndexedUpTo(); upTo != 7 {
+		t.Fatalf("expected watermark to stay at 7 mid-section, got %d", upTo)
+	}
+}
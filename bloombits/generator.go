@@ -0,0 +1,72 @@
+package bloombits
+
+import (
+	"errors"
+
+	"github.com/vechain/thor/thor"
+)
+
+// ErrSectionOutOfBounds is returned when the bit being added falls outside
+// the section currently being generated, or is added out of order.
+var ErrSectionOutOfBounds = errors.New("bloombits: section out of bounds")
+
+// Generator takes the blooms of a run of consecutive blocks (a "section")
+// and rotates them into thor.BloomBitLength bit-vectors, one per bit
+// position, each sectionSize bits long. Storing these bitvectors instead of
+// the blooms themselves is what lets a Matcher test many blocks against a
+// query with a handful of reads instead of one read per block.
+type Generator struct {
+	sectionSize uint64
+	bits        [thor.BloomBitLength][]byte
+	nextIndex   uint64
+}
+
+// NewGenerator creates a rotated bloom section generator for sections of
+// sectionSize blocks.
+func NewGenerator(sectionSize uint64) (*Generator, error) {
+	if sectionSize%8 != 0 {
+		return nil, errors.New("bloombits: section size must be a multiple of 8")
+	}
+	g := &Generator{sectionSize: sectionSize}
+	for i := range g.bits {
+		g.bits[i] = make([]byte, sectionSize/8)
+	}
+	return g, nil
+}
+
+// AddBloom rotates the bloom of the index-th block of the section (0-based,
+// must be added in order) into the in-memory bit-vectors.
+func (g *Generator) AddBloom(index uint64, bloom thor.Bloom) error {
+	if index != g.nextIndex {
+		return ErrSectionOutOfBounds
+	}
+	if index >= g.sectionSize {
+		return ErrSectionOutOfBounds
+	}
+
+	byteIndex := index / 8
+	bitMask := byte(1) << byte(7-index%8)
+
+	for bit := 0; bit < thor.BloomBitLength; bit++ {
+		bloomByteIndex := thor.BloomByteLength - 1 - bit/8
+		bloomBitMask := byte(1) << byte(bit%8)
+
+		if bloom[bloomByteIndex]&bloomBitMask != 0 {
+			g.bits[bit][byteIndex] |= bitMask
+		}
+	}
+	g.nextIndex++
+	return nil
+}
+
+// Bitset returns the rotated bit-vector for the given bit position. It can
+// only be called once every block of the section has been added.
+func (g *Generator) Bitset(bit uint) ([]byte, error) {
+	if g.nextIndex != g.sectionSize {
+		return nil, errors.New("bloombits: section not fully generated yet")
+	}
+	if bit >= uint(thor.BloomBitLength) {
+		return nil, ErrSectionOutOfBounds
+	}
+	return g.bits[bit], nil
+}
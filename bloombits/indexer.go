@@ -0,0 +1,127 @@
+package bloombits
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/vechain/thor/thor"
+)
+
+// SectionSize is the number of consecutive blocks grouped into one
+// bloom-bits section.
+const SectionSize = 4096
+
+// KeyValueStore is the minimal persistence interface the indexer and matcher
+// need out of lvldb, so neither has to depend on its concrete type.
+type KeyValueStore interface {
+	Get(key []byte) ([]byte, error)
+	Put(key []byte, value []byte) error
+}
+
+// bitKey builds the storage key a single section's rotated bit-vector is
+// kept under: bloom-bit-{bit}-{section}.
+func bitKey(bit uint, section uint64) []byte {
+	return []byte(fmt.Sprintf("bloom-bit-%d-%d", bit, section))
+}
+
+// Indexer consumes block blooms one at a time, in block-number order, and
+// persists a rotated bit-vector index every sectionSize blocks so a Matcher
+// can later answer range queries without a linear scan of the logs.
+type Indexer struct {
+	db          KeyValueStore
+	sectionSize uint64
+
+	section uint64
+	gen     *Generator
+
+	// indexed is atomically updated to the highest block number covered by a
+	// fully persisted section; -1 means no section has been persisted yet.
+	// Callers (the REST /logs handler in particular) must treat anything
+	// above it as unindexed and fall back to a linear scan instead of
+	// querying the Matcher for sections that were never written.
+	indexed int64
+}
+
+// NewIndexer creates an Indexer writing sectionSize-block sections to db.
+func NewIndexer(db KeyValueStore, sectionSize uint64) (*Indexer, error) {
+	gen, err := NewGenerator(sectionSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Indexer{
+		db:          db,
+		sectionSize: sectionSize,
+		gen:         gen,
+		indexed:     -1,
+	}, nil
+}
+
+// Add feeds the bloom of block number into the index, flushing a completed
+// section to disk whenever number crosses a section boundary.
+func (idx *Indexer) Add(number uint64, bloom thor.Bloom) error {
+	section := number / idx.sectionSize
+	if section != idx.section {
+		// number belongs to a new section; the previous one must already be
+		// complete, since sections are processed strictly in order.
+		return fmt.Errorf("bloombits: block %d does not belong to section %d", number, idx.section)
+	}
+
+	if err := idx.gen.AddBloom(number%idx.sectionSize, bloom); err != nil {
+		return err
+	}
+
+	if number%idx.sectionSize == idx.sectionSize-1 {
+		if err := idx.commit(); err != nil {
+			return err
+		}
+		atomic.StoreInt64(&idx.indexed, int64((idx.section+1)*idx.sectionSize)-1)
+		idx.section++
+		gen, err := NewGenerator(idx.sectionSize)
+		if err != nil {
+			return err
+		}
+		idx.gen = gen
+	}
+	return nil
+}
+
+func (idx *Indexer) commit() error {
+	for bit := 0; bit < thor.BloomBitLength; bit++ {
+		bitset, err := idx.gen.Bitset(uint(bit))
+		if err != nil {
+			return err
+		}
+		if err := idx.db.Put(bitKey(uint(bit), idx.section), bitset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexedUpTo returns the highest block number covered by sections fully
+// persisted to disk so far, and whether any section has been persisted at
+// all. Safe to call concurrently with Add.
+func (idx *Indexer) IndexedUpTo() (uint64, bool) {
+	v := atomic.LoadInt64(&idx.indexed)
+	if v < 0 {
+		return 0, false
+	}
+	return uint64(v), true
+}
+
+// Retrieve reads the persisted bit-vector for bit across sections, in the
+// shape Matcher expects of a retrieval function. Sections that were never
+// written (beyond IndexedUpTo) will error - callers are expected to only
+// request sections IndexedUpTo already covers.
+func (idx *Indexer) Retrieve(ctx context.Context, bit uint, sections []uint64) ([][]byte, error) {
+	out := make([][]byte, len(sections))
+	for i, section := range sections {
+		bitset, err := idx.db.Get(bitKey(bit, section))
+		if err != nil {
+			return nil, err
+		}
+		out[i] = bitset
+	}
+	return out, nil
+}
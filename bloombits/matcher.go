@@ -0,0 +1,180 @@
+package bloombits
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/vechain/thor/thor"
+	"golang.org/x/crypto/sha3"
+)
+
+// errAlreadyRunning is returned by Match when another session is already in
+// progress on the same Matcher.
+var errAlreadyRunning = errors.New("bloombits: matcher session already running")
+
+// bloomIndexes are the three bit positions (the standard k=3 bloom hashing,
+// see thor.Bloom) that an address or topic contributes to a block's bloom.
+type bloomIndexes [3]uint
+
+// calcBloomIndexes derives data's 3 bit positions the same way thor.Bloom's
+// own Add/Test do - from keccak256(data) directly via thor.BloomIndexes -
+// rather than materializing a Bloom and scanning it for set bits, which
+// would silently collapse to fewer than 3 distinct positions whenever two of
+// the three collide.
+func calcBloomIndexes(data []byte) bloomIndexes {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return bloomIndexes(thor.BloomIndexes(h.Sum(nil)))
+}
+
+// Matcher matches a filter of the shape `(A0 OR A1 OR ...) AND (B0 OR B1 OR
+// ...) AND ...` - each inner slice is one query term (e.g. the set of
+// addresses, or the set of alternative values for one topic position), and
+// each value in a term contributes 3 bloomIndexes - against the bloom-bits
+// index built by Indexer.
+type Matcher struct {
+	sectionSize uint64
+	terms       [][]bloomIndexes
+
+	retrieve func(ctx context.Context, bit uint, sections []uint64) ([][]byte, error)
+
+	running int32 // atomic; guards against concurrent sessions on the same Matcher
+}
+
+// NewMatcher creates a Matcher for the given filter. filter is a list of
+// terms; each term is a list of alternative address/topic values.
+func NewMatcher(sectionSize uint64, filter [][][]byte, retrieve func(ctx context.Context, bit uint, sections []uint64) ([][]byte, error)) *Matcher {
+	m := &Matcher{
+		sectionSize: sectionSize,
+		retrieve:    retrieve,
+	}
+	for _, term := range filter {
+		if len(term) == 0 {
+			continue
+		}
+		alts := make([]bloomIndexes, len(term))
+		for i, v := range term {
+			alts[i] = calcBloomIndexes(v)
+		}
+		m.terms = append(m.terms, alts)
+	}
+	return m
+}
+
+// Match runs the filter against sections [begin, end] (inclusive, expressed
+// as absolute block numbers) and returns, in ascending order, the numbers of
+// blocks that might contain a matching log. False positives are possible;
+// false negatives are not.
+func (m *Matcher) Match(ctx context.Context, begin, end uint64) ([]uint64, error) {
+	if !atomic.CompareAndSwapInt32(&m.running, 0, 1) {
+		return nil, errAlreadyRunning
+	}
+	defer atomic.StoreInt32(&m.running, 0)
+
+	if len(m.terms) == 0 {
+		return nil, nil
+	}
+
+	firstSection := begin / m.sectionSize
+	lastSection := end / m.sectionSize
+
+	sections := make([]uint64, 0, lastSection-firstSection+1)
+	for s := firstSection; s <= lastSection; s++ {
+		sections = append(sections, s)
+	}
+
+	// schedule, per term/alternative, the retrieval of its 3 bit-vectors in
+	// parallel - distinct terms need not wait on one another.
+	type fetched struct {
+		termIdx int
+		altIdx  int
+		bits    [3][][]byte // [bit position][section] -> bitset
+		err     error
+	}
+	results := make(chan fetched, countAlts(m.terms))
+
+	var wg sync.WaitGroup
+	for ti, term := range m.terms {
+		for ai, alt := range term {
+			wg.Add(1)
+			go func(ti, ai int, alt bloomIndexes) {
+				defer wg.Done()
+				var f fetched
+				f.termIdx, f.altIdx = ti, ai
+				for i, bit := range alt {
+					bitsets, err := m.retrieve(ctx, bit, sections)
+					if err != nil {
+						f.err = err
+						results <- f
+						return
+					}
+					f.bits[i] = bitsets
+				}
+				results <- f
+			}(ti, ai, alt)
+		}
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// termMatch[ti] is, per section-relative bit index, whether any
+	// alternative of term ti matched.
+	nBits := int(m.sectionSize) * len(sections)
+	termMatch := make([][]bool, len(m.terms))
+	for i := range termMatch {
+		termMatch[i] = make([]bool, nBits)
+	}
+
+	for f := range results {
+		if f.err != nil {
+			return nil, f.err
+		}
+		for localBit := 0; localBit < nBits; localBit++ {
+			sec := localBit / int(m.sectionSize)
+			within := uint(localBit % int(m.sectionSize))
+			if bitSet(f.bits[0][sec], within) && bitSet(f.bits[1][sec], within) && bitSet(f.bits[2][sec], within) {
+				termMatch[f.termIdx][localBit] = true
+			}
+		}
+	}
+
+	var matches []uint64
+	for localBit := 0; localBit < nBits; localBit++ {
+		ok := true
+		for _, tm := range termMatch {
+			if !tm[localBit] {
+				ok = false
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+		number := firstSection*m.sectionSize + uint64(localBit)
+		if number < begin || number > end {
+			continue
+		}
+		matches = append(matches, number)
+	}
+	return matches, nil
+}
+
+func bitSet(bitset []byte, index uint) bool {
+	if int(index/8) >= len(bitset) {
+		return false
+	}
+	return bitset[index/8]&(1<<(7-index%8)) != 0
+}
+
+func countAlts(terms [][]bloomIndexes) int {
+	n := 0
+	for _, t := range terms {
+		n += len(t)
+	}
+	return n
+}
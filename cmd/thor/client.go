@@ -18,6 +18,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/discover"
 	"github.com/vechain/thor/api"
 	"github.com/vechain/thor/block"
+	"github.com/vechain/thor/bloombits"
 	"github.com/vechain/thor/chain"
 	"github.com/vechain/thor/co"
 	"github.com/vechain/thor/comm"
@@ -29,6 +30,7 @@ import (
 	"github.com/vechain/thor/packer"
 	"github.com/vechain/thor/state"
 	"github.com/vechain/thor/thor"
+	"github.com/vechain/thor/tx"
 	"github.com/vechain/thor/txpool"
 	cli "gopkg.in/urfave/cli.v1"
 )
@@ -41,11 +43,12 @@ var (
 
 // Options for Client.
 type Options struct {
-	DataPath    string
-	Bind        string
-	Proposer    thor.Address
-	Beneficiary thor.Address
-	PrivateKey  *ecdsa.PrivateKey
+	DataPath       string
+	Bind           string
+	Proposer       thor.Address
+	Beneficiary    thor.Address
+	PrivateKey     *ecdsa.PrivateKey
+	EnablePrefetch bool
 }
 
 func newApp() *cli.App {
@@ -87,6 +90,10 @@ func newApp() *cli.App {
 			Name:  "vmodule",
 			Usage: "log verbosity pattern",
 		},
+		cli.BoolFlag{
+			Name:  "enable-prefetch",
+			Usage: "warm trie/code caches by speculatively executing upcoming txs while waiting to pack",
+		},
 	}
 	app.Action = action
 
@@ -121,6 +128,17 @@ func action(ctx *cli.Context) error {
 	}
 	defer ldb.Close()
 
+	bloomDB, err := lvldb.New(ctx.String("datadir")+"/bloombits.db", lvldb.Options{})
+	if err != nil {
+		return err
+	}
+	defer bloomDB.Close()
+
+	indexer, err := bloombits.NewIndexer(bloomDB, bloombits.SectionSize)
+	if err != nil {
+		return err
+	}
+
 	stateCreator := state.NewCreator(lv)
 
 	genesisBlock, _, err := genesis.Dev.Build(stateCreator)
@@ -157,7 +175,7 @@ func action(ctx *cli.Context) error {
 	defer lsr.Close()
 
 	txpl := txpool.New()
-	txIter, err := txpl.NewIterator(ch, stateCreator)
+	txIter, err := txpl.NewTransactionsByPriceAndNonce(ch, stateCreator)
 	if err != nil {
 		return err
 	}
@@ -169,6 +187,8 @@ func action(ctx *cli.Context) error {
 		newBlockPacked:  make(chan *block.Block),
 		newBlockAck:     make(chan struct{}),
 		bestBlockUpdate: make(chan struct{}),
+		enablePrefetch:  ctx.Bool("enable-prefetch"),
+		indexer:         indexer,
 	}
 
 	goes.Go(func() {
@@ -204,7 +224,17 @@ func action(ctx *cli.Context) error {
 	})
 
 	goes.Go(func() {
-		restful := http.Server{Handler: api.NewHTTPHandler(ch, stateCreator, txpl, ldb)}
+		mux := http.NewServeMux()
+		mux.Handle("/logs", newLogsHandler(ldb, indexer.Retrieve, indexer.IndexedUpTo, func() uint32 {
+			best, err := ch.GetBestBlock()
+			if err != nil {
+				return 0
+			}
+			return best.Header().Number()
+		}))
+		mux.Handle("/", api.NewHTTPHandler(ch, stateCreator, txpl, ldb))
+
+		restful := http.Server{Handler: mux}
 
 		go func() {
 			<-c.Done()
@@ -302,6 +332,8 @@ type events struct {
 	newBlockPacked  chan *block.Block
 	newBlockAck     chan struct{}
 	bestBlockUpdate chan struct{}
+	enablePrefetch  bool
+	indexer         *bloombits.Indexer
 }
 
 func (es *events) consent(ctx context.Context, blockCh chan *block.Block, cm *comm.Communicator, ch *chain.Chain, cs *consensus.Consensus) {
@@ -311,11 +343,20 @@ func (es *events) consent(ctx context.Context, blockCh chan *block.Block, cm *co
 			return
 		}
 		signer, _ := blk.Header().Signer()
-		if trunk, _, err := cs.Consent(blk, uint64(time.Now().Unix())); err == nil {
+		if trunk, receipts, err := cs.Consent(blk, uint64(time.Now().Unix())); err == nil {
 			ch.AddBlock(blk, trunk)
 			if trunk {
 				log.Info(fmt.Sprintf("received new block(#%v trunk)", blk.Header().Number()), "id", blk.Header().ID(), "size", blk.Size(), "proposer", signer)
 				cm.BroadcastBlock(blk)
+
+				var logs []*tx.Log
+				for _, receipt := range receipts {
+					logs = append(logs, receipt.Logs...)
+				}
+				if err := es.indexer.Add(uint64(blk.Header().Number()), tx.LogsBloom(logs)); err != nil {
+					log.Warn(fmt.Sprintf("failed to index block(#%v) logs bloom", blk.Header().Number()), "err", err.Error())
+				}
+
 				select {
 				case es.bestBlockUpdate <- struct{}{}:
 				default:
@@ -327,6 +368,8 @@ func (es *events) consent(ctx context.Context, blockCh chan *block.Block, cm *co
 			log.Warn(fmt.Sprintf("received new block(#%v bad)", blk.Header().Number()), "id", blk.Header().ID(), "size", blk.Size(), "proposer", signer, "err", err.Error())
 		}
 	case blk := <-es.newBlockPacked:
+		// Already indexed in pack() right after commit() succeeded, before
+		// blk was ever sent here - no second es.indexer.Add for this one.
 		if trunk, err := cs.IsTrunk(blk.Header()); err == nil {
 			ch.AddBlock(blk, trunk)
 			if trunk {
@@ -343,7 +386,7 @@ func (es *events) pack(
 	ctx context.Context,
 	ch *chain.Chain,
 	pk *packer.Packer,
-	txIter *txpool.Iterator,
+	txIter *txpool.TransactionsByPriceAndNonce,
 	privateKey *ecdsa.PrivateKey) {
 
 	bestBlock, err := ch.GetBestBlock()
@@ -359,24 +402,95 @@ func (es *events) pack(
 		waitTime := time.NewTimer(time.Duration(waitSec) * time.Second)
 		defer waitTime.Stop()
 
+		interruptCh := make(chan struct{})
+		prefetchDone := make(chan struct{})
+		if es.enablePrefetch {
+			go func() {
+				defer close(prefetchDone)
+				// Prefetch walks its own cloned cursor: Shift() is
+				// destructive, so reusing txIter itself here would
+				// permanently consume the very txs the real adopt loop
+				// below still needs to see.
+				prefetchPending(ctx, pk, bestBlock.Header(), txIter.Clone(), interruptCh)
+			}()
+		} else {
+			close(prefetchDone)
+		}
+
+		stopPrefetch := func() {
+			close(interruptCh)
+			<-prefetchDone
+		}
+
 		select {
 		case <-waitTime.C:
-			for txIter.HasNext() {
-				err := adopt(txIter.Next())
+			stopPrefetch()
+			for {
+				next := txIter.Peek()
+				if next == nil {
+					break
+				}
+				err := adopt(next)
 				if packer.IsGasLimitReached(err) {
 					break
 				}
+				txIter.Shift()
 			}
 
-			if blk, _, err := commit(privateKey); err == nil {
+			if blk, receipts, err := commit(privateKey); err == nil {
 				log.Info(fmt.Sprintf("proposed new block(#%v)", blk.Header().Number()), "id", blk.Header().ID(), "size", blk.Size())
+
+				var logs []*tx.Log
+				for _, receipt := range receipts {
+					logs = append(logs, receipt.Logs...)
+				}
+				if err := es.indexer.Add(uint64(blk.Header().Number()), tx.LogsBloom(logs)); err != nil {
+					log.Warn(fmt.Sprintf("failed to index block(#%v) logs bloom", blk.Header().Number()), "err", err.Error())
+				}
+
 				es.newBlockPacked <- blk
 				<-es.newBlockAck
 			}
 		case <-es.bestBlockUpdate:
+			stopPrefetch()
 			return
 		case <-ctx.Done():
+			stopPrefetch()
 			return
 		}
 	}
-}
\ No newline at end of file
+}
+
+// prefetchPending walks txIter - expected to be an independent Clone() of
+// the iterator the real packing loop uses - in price-and-nonce order,
+// speculatively warming trie/code caches for each tx via pk.Prefetch, until
+// it's interrupted or runs out of txs. Because it only ever mutates its own
+// clone, the real loop's view of the shared iterator is untouched; the two
+// happen to walk the same ordering, which is what makes the warmed cache
+// useful once adopt() catches up.
+func prefetchPending(
+	ctx context.Context,
+	pk *packer.Packer,
+	parent *block.Header,
+	txIter *txpool.TransactionsByPriceAndNonce,
+	interruptCh <-chan struct{}) {
+
+	for {
+		select {
+		case <-interruptCh:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		next := txIter.Peek()
+		if next == nil {
+			return
+		}
+		if err := pk.Prefetch(ctx, parent, next); err != nil {
+			return
+		}
+		txIter.Shift()
+	}
+}
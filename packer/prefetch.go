@@ -0,0 +1,45 @@
+package packer
+
+import (
+	"context"
+
+	"github.com/vechain/thor/block"
+	"github.com/vechain/thor/tx"
+	"github.com/vechain/thor/vm/statedb"
+)
+
+// Prefetch speculatively touches the storage a transaction is likely to read
+// or write - the signer's balance, and every clause recipient's code and
+// balance - against a throwaway copy of the state built on top of parent.
+// Every side effect is discarded via StateDB.Snapshot/RevertToSnapshot, so
+// concurrent, repeated prefetching can never corrupt the state the packer is
+// actually building for the next block. The point isn't correct execution;
+// it's pulling the trie nodes and contract code the real adopt() call will
+// need into the in-memory cache ahead of time.
+func (p *Packer) Prefetch(ctx context.Context, parent *block.Header, txn *tx.Transaction) error {
+	st, err := p.stateCreator.NewState(parent.StateRoot())
+	if err != nil {
+		return err
+	}
+
+	db := statedb.New(st)
+	rev := db.Snapshot()
+	defer db.RevertToSnapshot(rev)
+
+	signer, err := txn.Signer()
+	if err != nil {
+		return err
+	}
+	st.GetBalance(signer)
+
+	for _, clause := range txn.Clauses() {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if to := clause.To(); to != nil {
+			st.GetCode(*to)
+			st.GetBalance(*to)
+		}
+	}
+	return nil
+}
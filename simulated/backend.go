@@ -0,0 +1,234 @@
+// Package simulated provides an in-process Backend that wires together a
+// memory-backed chain, state and packer with no networking, so contracts and
+// API consumers can be exercised against real Thor semantics from a unit
+// test, without standing up p2psrv/comm/consensus.
+package simulated
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/vechain/thor/block"
+	"github.com/vechain/thor/chain"
+	"github.com/vechain/thor/genesis"
+	"github.com/vechain/thor/lvldb"
+	"github.com/vechain/thor/packer"
+	"github.com/vechain/thor/runtime"
+	"github.com/vechain/thor/state"
+	"github.com/vechain/thor/thor"
+	"github.com/vechain/thor/tx"
+	"github.com/vechain/thor/vm/statedb"
+)
+
+// Backend is an in-process Thor node: a memory lvldb, a dev-genesis chain, a
+// state creator and a packer, with no p2p or consensus loop. Txs sent to it
+// only take effect once Commit is called.
+type Backend struct {
+	chain        *chain.Chain
+	stateCreator *state.Creator
+	packer       *packer.Packer
+
+	timeOffset int64
+	pending    []*tx.Transaction
+}
+
+// NewBackend creates a Backend seeded with the dev genesis block, packing
+// blocks as proposer.
+func NewBackend(proposer thor.Address) (*Backend, error) {
+	lv, err := lvldb.NewMem()
+	if err != nil {
+		return nil, err
+	}
+
+	stateCreator := state.NewCreator(lv)
+	genesisBlock, _, err := genesis.Dev.Build(stateCreator)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := chain.New(lv)
+	if err := ch.WriteGenesis(genesisBlock); err != nil {
+		return nil, err
+	}
+
+	return &Backend{
+		chain:        ch,
+		stateCreator: stateCreator,
+		packer:       packer.New(ch, stateCreator, proposer, proposer),
+	}, nil
+}
+
+// SendTransaction queues txn to be included by the next Commit.
+func (b *Backend) SendTransaction(txn *tx.Transaction) error {
+	b.pending = append(b.pending, txn)
+	return nil
+}
+
+// AdjustTime skips the backend's clock forward by d, so time-locked
+// contracts can be exercised without actually waiting.
+func (b *Backend) AdjustTime(d time.Duration) {
+	b.timeOffset += int64(d / time.Second)
+}
+
+// Commit seals a new block containing the txs queued via SendTransaction
+// since the last Commit. A queued tx that no longer fits the block's gas
+// limit is left out of the block and dropped from the queue along with the
+// rest.
+func (b *Backend) Commit(privateKey *ecdsa.PrivateKey) (*block.Block, error) {
+	best, err := b.chain.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	now := uint64(time.Now().Unix() + b.timeOffset)
+	_, adopt, commit, err := b.packer.Prepare(best.Header(), now)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, txn := range b.pending {
+		if err := adopt(txn); err != nil && !packer.IsGasLimitReached(err) {
+			return nil, err
+		}
+	}
+	b.pending = nil
+
+	blk, _, err := commit(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.chain.AddBlock(blk, true); err != nil {
+		return nil, err
+	}
+	return blk, nil
+}
+
+// CallContract executes clause against the current best block's confirmed
+// state - ignoring anything only queued via SendTransaction, unlike
+// PendingCallContract - and discards every side effect.
+func (b *Backend) CallContract(ctx context.Context, clause *tx.Clause, caller thor.Address) (output []byte, err error) {
+	header, st, err := b.confirmedState()
+	if err != nil {
+		return nil, err
+	}
+	return callClause(header, st, clause, caller)
+}
+
+// PendingCallContract executes clause against the pending block's state -
+// i.e. on top of whatever txs are already queued via SendTransaction - and
+// reverts every side effect before returning, even if the call panics, so a
+// failed call or estimation never corrupts the pending state for the next
+// caller.
+func (b *Backend) PendingCallContract(ctx context.Context, clause *tx.Clause, caller thor.Address) (output []byte, err error) {
+	header, st, err := b.pendingState()
+	if err != nil {
+		return nil, err
+	}
+	return callClause(header, st, clause, caller)
+}
+
+// callClause executes clause against header/st and reverts every side
+// effect before returning, even if the call panics, so a failed call or
+// estimation never corrupts st for the next caller.
+func callClause(header *block.Header, st state.State, clause *tx.Clause, caller thor.Address) (output []byte, err error) {
+	db := statedb.New(st)
+	rev := db.Snapshot()
+	defer func() {
+		db.RevertToSnapshot(rev)
+		if r := recover(); r != nil {
+			err = fmt.Errorf("simulated: call panicked: %v", r)
+		}
+	}()
+
+	out, execErr := runtime.New(db, header).ExecuteClause(clause, caller)
+	if execErr != nil {
+		return nil, execErr
+	}
+	return out.Value, out.VMErr
+}
+
+// EstimateGas returns the gas clause would consume if executed against the
+// pending state, without mutating it.
+func (b *Backend) EstimateGas(ctx context.Context, clause *tx.Clause, caller thor.Address) (gas uint64, err error) {
+	header, st, err := b.pendingState()
+	if err != nil {
+		return 0, err
+	}
+
+	db := statedb.New(st)
+	rev := db.Snapshot()
+	defer func() {
+		db.RevertToSnapshot(rev)
+		if r := recover(); r != nil {
+			err = fmt.Errorf("simulated: estimate panicked: %v", r)
+		}
+	}()
+
+	out, execErr := runtime.New(db, header).ExecuteClause(clause, caller)
+	if execErr != nil {
+		return 0, execErr
+	}
+	return out.GasUsed, out.VMErr
+}
+
+// confirmedState returns the best block's header and state, ignoring
+// anything only queued via SendTransaction.
+func (b *Backend) confirmedState() (*block.Header, state.State, error) {
+	best, err := b.chain.GetBestBlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	st, err := b.stateCreator.NewState(best.Header().StateRoot())
+	if err != nil {
+		return nil, nil, err
+	}
+	return best.Header(), st, nil
+}
+
+// pendingState builds the header/state pair the pending block would commit
+// on top of: the best block's state with the txs queued via SendTransaction
+// since the last Commit already adopted (those that no longer fit the gas
+// limit are skipped). It mirrors Commit's own
+// Prepare/adopt sequence, except the resulting block is never sealed to the
+// chain - the ephemeral key only satisfies commit's signature requirement
+// and is discarded along with the block itself.
+func (b *Backend) pendingState() (*block.Header, state.State, error) {
+	if len(b.pending) == 0 {
+		return b.confirmedState()
+	}
+
+	best, err := b.chain.GetBestBlock()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := uint64(time.Now().Unix() + b.timeOffset)
+	_, adopt, commit, err := b.packer.Prepare(best.Header(), now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, txn := range b.pending {
+		if err := adopt(txn); err != nil && !packer.IsGasLimitReached(err) {
+			return nil, nil, err
+		}
+	}
+
+	ephemeralKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	blk, _, err := commit(ephemeralKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	st, err := b.stateCreator.NewState(blk.Header().StateRoot())
+	if err != nil {
+		return nil, nil, err
+	}
+	return blk.Header(), st, nil
+}
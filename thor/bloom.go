@@ -0,0 +1,62 @@
+package thor
+
+import (
+	"golang.org/x/crypto/sha3"
+)
+
+// BloomByteLength is the number of bytes used in a header log bloom, giving
+// 2048 bits.
+const BloomByteLength = 256
+
+// BloomBitLength is the number of bits in a bloom filter.
+const BloomBitLength = 8 * BloomByteLength
+
+// Bloom represents a 2048-bit bloom filter, attached to a block header so
+// that "does block N possibly contain logs from address A / topic T" can be
+// answered without fetching or decoding the block's logs.
+type Bloom [BloomByteLength]byte
+
+// Add sets the bits of b that the keccak256 hash of data maps to.
+func (b *Bloom) Add(data []byte) {
+	for _, i := range BloomIndexes(keccak256(data)) {
+		b[BloomByteLength-1-i/8] |= 1 << (i % 8)
+	}
+}
+
+// Test reports whether data is possibly a member of the filter. A false
+// result is conclusive; a true result may be a false positive.
+func (b Bloom) Test(data []byte) bool {
+	for _, i := range BloomIndexes(keccak256(data)) {
+		if b[BloomByteLength-1-i/8]&(1<<(i%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Bytes returns the byte representation of b.
+func (b Bloom) Bytes() []byte {
+	return b[:]
+}
+
+// BloomIndexes returns the three bit positions, each in [0, BloomBitLength),
+// that a keccak256 hash contributes to a Bloom, following the standard k=3
+// scheme: every pair of bytes of the hash, taken from the front, is reduced
+// mod BloomBitLength. It's exported so callers that need the same positions
+// without a materialized Bloom - the bloombits Matcher in particular - derive
+// them the same way Add/Test do, instead of reverse-engineering them from a
+// Bloom's set bits (which is ambiguous whenever two of the three positions
+// collide).
+func BloomIndexes(hash []byte) [3]uint {
+	var idxs [3]uint
+	for i := 0; i < 3; i++ {
+		idxs[i] = (uint(hash[2*i])<<8 | uint(hash[2*i+1])) & (BloomBitLength - 1)
+	}
+	return idxs
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
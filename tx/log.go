@@ -0,0 +1,49 @@
+package tx
+
+import (
+	"github.com/vechain/thor/thor"
+)
+
+// Log is the canonical record of a contract LOG event, as emitted by the
+// EVM and consumed by logdb, the REST api, and block-broadcast paths. It's
+// the single type those packages share, so none of them need to depend on
+// the EVM adapter package just to read a log.
+type Log struct {
+	// Address is the contract that generated the event.
+	Address thor.Address
+	// Topics are the indexed topics provided by the contract.
+	Topics []thor.Hash
+	// Data is the non-indexed data, usually ABI-encoded.
+	Data []byte
+
+	// BlockNumber is the number of the block the log was included in.
+	BlockNumber uint32
+	// TxHash is the hash of the transaction that generated the log.
+	TxHash thor.Hash
+	// TxIndex is the index of the transaction within the block.
+	TxIndex uint32
+	// LogIndex is the index of the log within the block.
+	LogIndex uint32
+	// Removed is true if the log was reverted due to a chain reorganisation.
+	Removed bool
+}
+
+// Bloom derives the bloom filter value for l, obtained by adding the
+// contract address and every topic.
+func (l *Log) Bloom(b *thor.Bloom) {
+	b.Add(l.Address[:])
+	for _, topic := range l.Topics {
+		b.Add(topic[:])
+	}
+}
+
+// LogsBloom derives the 2048-bit bloom filter for a set of logs, so a
+// client can later ask "does this block possibly contain logs from address A
+// / topic T" without scanning the logs themselves.
+func LogsBloom(logs []*Log) thor.Bloom {
+	var b thor.Bloom
+	for _, log := range logs {
+		log.Bloom(&b)
+	}
+	return b
+}
@@ -0,0 +1,37 @@
+package tx
+
+import (
+	"testing"
+
+	"github.com/vechain/thor/thor"
+)
+
+func TestLogsBloom(t *testing.T) {
+	addr := thor.Address{0x01}
+	topic := thor.Hash{0x02}
+
+	logs := []*Log{
+		{Address: addr, Topics: []thor.Hash{topic}},
+	}
+
+	bloom := LogsBloom(logs)
+
+	if !bloom.Test(addr[:]) {
+		t.Fatal("expected bloom to test positive for the log's address")
+	}
+	if !bloom.Test(topic[:]) {
+		t.Fatal("expected bloom to test positive for the log's topic")
+	}
+	other := thor.Address{0xff}
+	if bloom.Test(other[:]) {
+		t.Fatal("expected bloom to test negative for an address never added")
+	}
+}
+
+func TestLogsBloomEmpty(t *testing.T) {
+	bloom := LogsBloom(nil)
+	var want thor.Bloom
+	if bloom != want {
+		t.Fatal("expected LogsBloom(nil) to be the zero bloom")
+	}
+}
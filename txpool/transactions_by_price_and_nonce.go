@@ -0,0 +1,189 @@
+package txpool
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/vechain/thor/chain"
+	"github.com/vechain/thor/state"
+	"github.com/vechain/thor/thor"
+	"github.com/vechain/thor/tx"
+)
+
+// TransactionsByPriceAndNonce represents pending transactions, grouped by
+// sender and ordered by nonce within each sender, that can be retrieved in an
+// overall price-descending order. Unlike a plain FIFO iterator it lets the
+// caller skip an entire sender (e.g. because it ran out of gas in the block
+// being packed) without losing the rest of the pool's ordering.
+//
+// Peek/Shift/Forward are safe to call concurrently: Forward is expected to be
+// invoked from outside the packer's own Peek/Shift loop (e.g. when the pool
+// learns a tx was included by some other means), so byAddr and heads are
+// guarded by mu rather than left to the caller to serialize.
+type TransactionsByPriceAndNonce struct {
+	mu     sync.Mutex
+	byAddr map[thor.Address][]*tx.Transaction
+	heads  txsByGasPrice
+}
+
+// NewTransactionsByPriceAndNonce creates an iterator over the txs currently
+// pending in the pool, grouped by sender address. Within a sender, txs are
+// kept in nonce order; across senders, the iterator always surfaces the
+// highest gas-priced head first.
+func (p *Pool) NewTransactionsByPriceAndNonce(ch *chain.Chain, stateCreator *state.Creator) (*TransactionsByPriceAndNonce, error) {
+	byAddr, err := p.pendingBySender(ch, stateCreator)
+	if err != nil {
+		return nil, err
+	}
+
+	heads := make(txsByGasPrice, 0, len(byAddr))
+	for _, txs := range byAddr {
+		if len(txs) > 0 {
+			heads = append(heads, txs[0])
+		}
+	}
+	heap.Init(&heads)
+
+	return &TransactionsByPriceAndNonce{
+		byAddr: byAddr,
+		heads:  heads,
+	}, nil
+}
+
+// Peek returns the best transaction by gas price, without consuming it. It
+// returns nil when there's nothing left to iterate.
+func (it *TransactionsByPriceAndNonce) Peek() *tx.Transaction {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if len(it.heads) == 0 {
+		return nil
+	}
+	return it.heads[0]
+}
+
+// Shift advances past the current best transaction, replacing it with the
+// next tx of the same sender (if any), and re-heapifies.
+func (it *TransactionsByPriceAndNonce) Shift() {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if len(it.heads) == 0 {
+		return
+	}
+	signer, _ := it.heads[0].Signer()
+	rest := it.byAddr[signer][1:]
+	it.byAddr[signer] = rest
+
+	if len(rest) == 0 {
+		heap.Pop(&it.heads)
+		return
+	}
+	it.heads[0] = rest[0]
+	heap.Fix(&it.heads, 0)
+}
+
+// Forward fast-forwards past all queued txs of the same sender as txn whose
+// nonce is lower than or equal to txn's nonce. It's used when a tx of that
+// sender is learned to have been included by some other means, so the
+// iterator doesn't hand it (or its now-stale successors) out again.
+func (it *TransactionsByPriceAndNonce) Forward(txn *tx.Transaction) {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	if len(it.heads) == 0 {
+		return
+	}
+	signer, _ := txn.Signer()
+	txs, ok := it.byAddr[signer]
+	if !ok {
+		return
+	}
+
+	i := 0
+	for i < len(txs) && txs[i].Nonce() <= txn.Nonce() {
+		i++
+	}
+	txs = txs[i:]
+	it.byAddr[signer] = txs
+
+	for i, head := range it.heads {
+		if headSigner, _ := head.Signer(); headSigner == signer {
+			if len(txs) == 0 {
+				heap.Remove(&it.heads, i)
+			} else {
+				it.heads[i] = txs[0]
+				heap.Fix(&it.heads, i)
+			}
+			return
+		}
+	}
+}
+
+// Clone returns an independent copy of it: a separate cursor over the same
+// set of pending txs, whose own Peek/Shift/Forward calls never affect it (or
+// vice versa). This is what lets a speculative walk - e.g. block-packing
+// prefetch - consume its own copy of the ordering without stealing txs from
+// whichever caller owns the original iterator.
+func (it *TransactionsByPriceAndNonce) Clone() *TransactionsByPriceAndNonce {
+	it.mu.Lock()
+	defer it.mu.Unlock()
+
+	byAddr := make(map[thor.Address][]*tx.Transaction, len(it.byAddr))
+	for addr, txs := range it.byAddr {
+		byAddr[addr] = append([]*tx.Transaction(nil), txs...)
+	}
+
+	heads := make(txsByGasPrice, len(it.heads))
+	for i, head := range it.heads {
+		signer, _ := head.Signer()
+		heads[i] = byAddr[signer][0]
+	}
+
+	return &TransactionsByPriceAndNonce{
+		byAddr: byAddr,
+		heads:  heads,
+	}
+}
+
+// txsByGasPrice is a container/heap of transaction heads ordered by
+// descending gas price.
+type txsByGasPrice []*tx.Transaction
+
+func (h txsByGasPrice) Len() int { return len(h) }
+func (h txsByGasPrice) Less(i, j int) bool {
+	return h[i].GasPrice().Cmp(h[j].GasPrice()) > 0
+}
+func (h txsByGasPrice) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *txsByGasPrice) Push(x interface{}) {
+	*h = append(*h, x.(*tx.Transaction))
+}
+
+func (h *txsByGasPrice) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// pendingBySender loads all pending, executable transactions from the pool
+// (the same set the old FIFO Iterator walked) and groups them by sender
+// address, each group kept in nonce order.
+func (p *Pool) pendingBySender(ch *chain.Chain, stateCreator *state.Creator) (map[thor.Address][]*tx.Transaction, error) {
+	txs, err := p.executables(ch, stateCreator)
+	if err != nil {
+		return nil, err
+	}
+
+	byAddr := make(map[thor.Address][]*tx.Transaction)
+	for _, txn := range txs {
+		signer, err := txn.Signer()
+		if err != nil {
+			continue
+		}
+		byAddr[signer] = append(byAddr[signer], txn)
+	}
+	return byAddr, nil
+}
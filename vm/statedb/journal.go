@@ -0,0 +1,115 @@
+package statedb
+
+import (
+	"math/big"
+
+	"github.com/vechain/thor/thor"
+)
+
+// journalEntry is a single state mutation recorded by StateDB, along with
+// enough information to undo it.
+type journalEntry interface {
+	revert(*StateDB)
+}
+
+// journal is an ordered log of mutations applied to a StateDB since it was
+// created. Snapshot/RevertToSnapshot are implemented by slicing into this
+// log and reverting entries one at a time, tail first, instead of
+// checkpointing and restoring the whole underlying trie on every CALL/CREATE
+// sub-call.
+type journal struct {
+	entries []journalEntry
+}
+
+func newJournal() *journal {
+	return &journal{}
+}
+
+func (j *journal) append(entry journalEntry) {
+	j.entries = append(j.entries, entry)
+}
+
+func (j *journal) length() int {
+	return len(j.entries)
+}
+
+// revertTo reverts entries recorded after the given revision, in reverse
+// order, then truncates the log to that revision.
+func (j *journal) revertTo(s *StateDB, revision int) {
+	for i := len(j.entries) - 1; i >= revision; i-- {
+		j.entries[i].revert(s)
+	}
+	j.entries = j.entries[:revision]
+}
+
+type (
+	balanceChange struct {
+		addr thor.Address
+		prev *big.Int
+	}
+	nonceChange struct {
+		addr thor.Address
+		prev uint64
+	}
+	storageChange struct {
+		addr thor.Address
+		key  thor.Hash
+		prev thor.Hash
+	}
+	codeChange struct {
+		addr     thor.Address
+		prevCode []byte
+	}
+	// suicideChange undoes Suicide's only immediate state effects - the
+	// suicided flag and the zeroed balance. It never needs to restore code
+	// or storage, since Suicide no longer deletes the account outright;
+	// that's deferred to Finalize, which runs after reverting is no longer
+	// possible.
+	suicideChange struct {
+		addr        thor.Address
+		prevAlive   bool
+		prevBalance *big.Int
+	}
+	refundChange struct {
+		prev *big.Int
+	}
+	logChange      struct{}
+	preimageChange struct {
+		hash thor.Hash
+	}
+)
+
+func (c balanceChange) revert(s *StateDB) {
+	s.state.SetBalance(c.addr, c.prev)
+}
+
+func (c nonceChange) revert(s *StateDB) {
+	s.nonces[c.addr] = c.prev
+}
+
+func (c storageChange) revert(s *StateDB) {
+	s.state.SetStorage(c.addr, c.key, c.prev)
+}
+
+func (c codeChange) revert(s *StateDB) {
+	s.state.SetCode(c.addr, c.prevCode)
+}
+
+func (c suicideChange) revert(s *StateDB) {
+	if c.prevAlive {
+		delete(s.suicided, c.addr)
+		s.state.SetBalance(c.addr, c.prevBalance)
+	}
+}
+
+func (c refundChange) revert(s *StateDB) {
+	s.refund = c.prev
+}
+
+func (c logChange) revert(s *StateDB) {
+	s.logs = s.logs[:len(s.logs)-1]
+}
+
+func (c preimageChange) revert(s *StateDB) {
+	delete(s.preimages, c.hash)
+}
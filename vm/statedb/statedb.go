@@ -6,8 +6,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/vechain/thor/stackedmap"
 	"github.com/vechain/thor/thor"
+	"github.com/vechain/thor/tx"
 	"github.com/vechain/thor/vm/evm"
 )
 
@@ -17,61 +17,48 @@ var _ evm.StateDB = (*StateDB)(nil)
 // It implements evm.StateDB, only adapt to evm.
 type StateDB struct {
 	state State
-	repo  *stackedmap.StackedMap
-}
 
-type suicideFlagKey common.Address
-type preimageKey common.Hash
-type refundKey struct{}
-type logKey struct{}
+	journal   *journal
+	nonces    map[thor.Address]uint64
+	suicided  map[thor.Address]bool
+	refund    *big.Int
+	preimages map[thor.Hash][]byte
+	logs      []*tx.Log
+}
 
 // New create a statedb object.
 func New(state State) *StateDB {
-	getter := func(k interface{}) (interface{}, bool) {
-		switch k.(type) {
-		case suicideFlagKey:
-			return false, true
-		case refundKey:
-			return &big.Int{}, true
-		case preimageKey:
-			return []byte(nil), true
-		case logKey:
-			return (*types.Log)(nil), true
-		}
-		panic(fmt.Sprintf("unknown type of key %+v", k))
-	}
-
-	repo := stackedmap.New(getter)
 	return &StateDB{
-		state,
-		repo,
+		state:     state,
+		journal:   newJournal(),
+		nonces:    make(map[thor.Address]uint64),
+		suicided:  make(map[thor.Address]bool),
+		refund:    new(big.Int),
+		preimages: make(map[thor.Hash][]byte),
 	}
 }
 
 // GetRefund returns total refund during VM life-cycle.
 func (s *StateDB) GetRefund() *big.Int {
-	v, _ := s.repo.Get(refundKey{})
-	return v.(*big.Int)
+	return s.refund
 }
 
 // GetPreimages returns preimages produced by VM when evm.Config.EnablePreimageRecording turned on.
 func (s *StateDB) GetPreimages(cb func(thor.Hash, []byte) bool) {
-	s.repo.Journal(func(k, v interface{}) bool {
-		if key, ok := k.(preimageKey); ok {
-			return cb(thor.Hash(key), v.([]byte))
+	for hash, preimage := range s.preimages {
+		if !cb(hash, preimage) {
+			return
 		}
-		return true
-	})
+	}
 }
 
 // GetLogs return the logs collected during VM life-cycle.
-func (s *StateDB) GetLogs(cb func(*Log) bool) {
-	s.repo.Journal(func(k, v interface{}) bool {
-		if _, ok := k.(logKey); ok {
-			return cb(v.(*Log))
+func (s *StateDB) GetLogs(cb func(*tx.Log) bool) {
+	for _, log := range s.logs {
+		if !cb(log) {
+			return
 		}
-		return true
-	})
+	}
 }
 
 // ForEachStorage see state.State.ForEachStorage.
@@ -94,8 +81,10 @@ func (s *StateDB) SubBalance(addr common.Address, amount *big.Int) {
 	if amount.Sign() == 0 {
 		return
 	}
-	balance := s.state.GetBalance(thor.Address(addr))
-	s.state.SetBalance(thor.Address(addr), new(big.Int).Sub(balance, amount))
+	a := thor.Address(addr)
+	balance := s.state.GetBalance(a)
+	s.journal.append(balanceChange{addr: a, prev: balance})
+	s.state.SetBalance(a, new(big.Int).Sub(balance, amount))
 }
 
 // AddBalance stub.
@@ -103,15 +92,23 @@ func (s *StateDB) AddBalance(addr common.Address, amount *big.Int) {
 	if amount.Sign() == 0 {
 		return
 	}
-	balance := s.state.GetBalance(thor.Address(addr))
-	s.state.SetBalance(thor.Address(addr), new(big.Int).Add(balance, amount))
+	a := thor.Address(addr)
+	balance := s.state.GetBalance(a)
+	s.journal.append(balanceChange{addr: a, prev: balance})
+	s.state.SetBalance(a, new(big.Int).Add(balance, amount))
 }
 
 // GetNonce stub.
-func (s *StateDB) GetNonce(addr common.Address) uint64 { return 0 }
+func (s *StateDB) GetNonce(addr common.Address) uint64 {
+	return s.nonces[thor.Address(addr)]
+}
 
 // SetNonce stub.
-func (s *StateDB) SetNonce(addr common.Address, nonce uint64) {}
+func (s *StateDB) SetNonce(addr common.Address, nonce uint64) {
+	a := thor.Address(addr)
+	s.journal.append(nonceChange{addr: a, prev: s.nonces[a]})
+	s.nonces[a] = nonce
+}
 
 // GetCodeHash stub.
 func (s *StateDB) GetCodeHash(addr common.Address) common.Hash {
@@ -130,29 +127,55 @@ func (s *StateDB) GetCodeSize(addr common.Address) int {
 
 // SetCode stub.
 func (s *StateDB) SetCode(addr common.Address, code []byte) {
-	s.state.SetCode(thor.Address(addr), code)
+	a := thor.Address(addr)
+	s.journal.append(codeChange{addr: a, prevCode: s.state.GetCode(a)})
+	s.state.SetCode(a, code)
 }
 
 // HasSuicided stub.
 func (s *StateDB) HasSuicided(addr common.Address) bool {
-	// only check suicide flag here
-	v, _ := s.repo.Get(suicideFlagKey(addr))
-	return v.(bool)
+	return s.suicided[thor.Address(addr)]
 }
 
-// Suicide stub.
-// We do two things:
-// 1, delete account
-// 2, set suicide flag
+// Suicide marks addr to be removed once Finalize is called, and zeroes its
+// balance immediately - the only effect SELFDESTRUCT has within the current
+// transaction. The account, its code and its storage are left untouched in
+// state until Finalize, so an outer call that reverts this one (via
+// RevertToSnapshot) only ever needs to undo the suicided flag and the
+// balance, not reconstruct deleted code/storage that was never actually
+// deleted.
 func (s *StateDB) Suicide(addr common.Address) bool {
-	if !s.state.Exists(thor.Address(addr)) {
+	a := thor.Address(addr)
+	if !s.state.Exists(a) {
 		return false
 	}
-	s.state.Delete(thor.Address(addr))
-	s.repo.Put(suicideFlagKey(addr), true)
+	prevBalance := s.state.GetBalance(a)
+	if s.suicided[a] {
+		// already marked: only the balance changes, the flag must survive a revert
+		s.journal.append(balanceChange{addr: a, prev: prevBalance})
+	} else {
+		s.journal.append(suicideChange{
+			addr:        a,
+			prevAlive:   true,
+			prevBalance: prevBalance,
+		})
+	}
+	s.state.SetBalance(a, new(big.Int))
+	s.suicided[a] = true
 	return true
 }
 
+// Finalize permanently deletes every account Suicide has marked during this
+// StateDB's lifetime. It must only be called once the transaction has fully
+// succeeded - i.e. once no further RevertToSnapshot call can happen - since,
+// unlike every other mutation StateDB makes, the deletion itself isn't
+// journaled and can't be undone.
+func (s *StateDB) Finalize() {
+	for addr := range s.suicided {
+		s.state.Delete(addr)
+	}
+}
+
 // GetState stub.
 func (s *StateDB) GetState(addr common.Address, key common.Hash) common.Hash {
 	return common.Hash(s.state.GetStorage(thor.Address(addr), thor.Hash(key)))
@@ -160,7 +183,10 @@ func (s *StateDB) GetState(addr common.Address, key common.Hash) common.Hash {
 
 // SetState stub.
 func (s *StateDB) SetState(addr common.Address, key, value common.Hash) {
-	s.state.SetStorage(thor.Address(addr), thor.Hash(key), thor.Hash(value))
+	a := thor.Address(addr)
+	k := thor.Hash(key)
+	s.journal.append(storageChange{addr: a, key: k, prev: s.state.GetStorage(a, k)})
+	s.state.SetStorage(a, k, thor.Hash(value))
 }
 
 // Exist stub.
@@ -175,61 +201,52 @@ func (s *StateDB) Empty(addr common.Address) bool {
 
 // AddRefund stub.
 func (s *StateDB) AddRefund(gas *big.Int) {
-	v, _ := s.repo.Get(refundKey{})
-	total := new(big.Int).Add(v.(*big.Int), gas)
-	s.repo.Put(refundKey{}, total)
+	s.journal.append(refundChange{prev: s.refund})
+	s.refund = new(big.Int).Add(s.refund, gas)
 }
 
 // AddPreimage stub.
 func (s *StateDB) AddPreimage(hash common.Hash, preimage []byte) {
-	s.repo.Put(preimageKey(hash), preimage)
+	h := thor.Hash(hash)
+	if _, ok := s.preimages[h]; ok {
+		return
+	}
+	s.journal.append(preimageChange{hash: h})
+	s.preimages[h] = preimage
 }
 
 // AddLog stub.
 func (s *StateDB) AddLog(vmlog *types.Log) {
-	s.repo.Put(logKey{}, vmlogToLog(vmlog))
+	s.journal.append(logChange{})
+	s.logs = append(s.logs, vmlogToLog(vmlog))
 }
 
 // Snapshot stub.
 func (s *StateDB) Snapshot() int {
-	s.state.NewCheckpoint()
-	rev := s.repo.Push()
-	return rev
+	return s.journal.length()
 }
 
 // RevertToSnapshot stub.
 func (s *StateDB) RevertToSnapshot(rev int) {
-	if rev < 0 || rev > s.repo.Depth() {
-		panic(fmt.Sprintf("invalid snapshot revision %d (depth:%d)", rev, s.repo.Depth()))
-	}
-	revertCount := s.repo.Depth() - rev
-	for i := 0; i < revertCount; i++ {
-		s.state.Revert()
+	if rev < 0 || rev > s.journal.length() {
+		panic(fmt.Sprintf("invalid snapshot revision %d (depth:%d)", rev, s.journal.length()))
 	}
-	s.repo.PopTo(rev)
+	s.journal.revertTo(s, rev)
 }
 
-// Log represents a contract log event. These events are generated by the LOG opcode and
-// stored/indexed by the node.
-type Log struct {
-	// address of the contract that generated the event
-	Address thor.Address
-	// list of topics provided by the contract.
-	Topics []thor.Hash
-	// supplied by the contract, usually ABI-encoded
-	Data []byte
-}
-
-func vmlogToLog(vmlog *types.Log) *Log {
+// vmlogToLog converts a go-ethereum EVM log into the canonical tx.Log. Block,
+// tx and log position fields are filled in later by whoever finalizes the
+// block (the EVM itself doesn't know them).
+func vmlogToLog(vmlog *types.Log) *tx.Log {
 	var topics []thor.Hash
 	if len(vmlog.Topics) > 0 {
 		for _, t := range vmlog.Topics {
 			topics = append(topics, thor.Hash(t))
 		}
 	}
-	return &Log{
-		thor.Address(vmlog.Address),
-		topics,
-		vmlog.Data,
+	return &tx.Log{
+		Address: thor.Address(vmlog.Address),
+		Topics:  topics,
+		Data:    vmlog.Data,
 	}
-}
\ No newline at end of file
+}